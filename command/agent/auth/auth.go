@@ -0,0 +1,23 @@
+// Package auth defines the interface implemented by vault-agent auto_auth
+// methods and the shared config type used to construct them.
+package auth
+
+import (
+	log "github.com/hashicorp/go-hclog"
+)
+
+// AuthMethod is a mechanism for authenticating to Vault.
+type AuthMethod interface {
+	// Authenticate returns the mount path to log in against and the login
+	// request body to submit to it.
+	Authenticate() (path string, data map[string]interface{}, err error)
+}
+
+// AuthConfig is the configuration passed into an auth method's constructor,
+// containing the common bits all methods need plus the type-specific
+// values pulled from the HCL 'config' stanza.
+type AuthConfig struct {
+	Logger    log.Logger
+	MountPath string
+	Config    map[string]interface{}
+}
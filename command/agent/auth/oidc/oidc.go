@@ -0,0 +1,286 @@
+// Package oidc implements a vault-agent auto_auth method that performs an
+// OAuth2/OIDC authorization-code or device-code flow against an external
+// provider (Google, GitHub, GitLab, or any OIDC-compliant issuer) and
+// exchanges the resulting ID token with Vault's JWT auth backend.
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+
+	"github.com/hashicorp/vault/command/agent/auth"
+)
+
+// providerEndpoints holds the subset of a provider's OIDC discovery
+// document this method needs.
+type providerEndpoints struct {
+	AuthorizationEndpoint       string `json:"authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+}
+
+// OIDCMethod implements auth.AuthMethod via an OAuth2/OIDC flow.
+type OIDCMethod struct {
+	logger       log.Logger
+	client       *http.Client
+	issuerURL    string
+	provider     string
+	clientID     string
+	clientSecret string
+	role         string
+	flow         string
+	scopes       []string
+	mountPath    string
+
+	refreshToken string
+}
+
+// NewOIDCMethod creates an OIDC auth method from the given auth config.
+// It expects 'provider', 'client_id', 'issuer_url', 'role', and optionally
+// 'client_secret_file', 'scopes', and 'flow' in conf.Config, as validated
+// by config.parseMethod.
+func NewOIDCMethod(conf *auth.AuthConfig) (*OIDCMethod, error) {
+	o := &OIDCMethod{
+		logger:    conf.Logger,
+		client:    &http.Client{},
+		issuerURL: conf.Config["issuer_url"].(string),
+		provider:  conf.Config["provider"].(string),
+		clientID:  conf.Config["client_id"].(string),
+		role:      conf.Config["role"].(string),
+		flow:      "device",
+		mountPath: conf.MountPath,
+	}
+
+	if raw, ok := conf.Config["flow"]; ok {
+		o.flow = raw.(string)
+	}
+
+	if raw, ok := conf.Config["client_secret_file"]; ok {
+		b, err := ioutil.ReadFile(raw.(string))
+		if err != nil {
+			return nil, fmt.Errorf("error reading client_secret_file: %w", err)
+		}
+		o.clientSecret = strings.TrimSpace(string(b))
+	}
+
+	if raw, ok := conf.Config["scopes"]; ok {
+		for _, s := range raw.([]interface{}) {
+			o.scopes = append(o.scopes, s.(string))
+		}
+	}
+
+	return o, nil
+}
+
+// Authenticate runs the configured OIDC flow (reusing a refresh token from
+// a prior run when one is available) and returns the Vault JWT auth login
+// request for conf.role.
+func (o *OIDCMethod) Authenticate() (string, map[string]interface{}, error) {
+	idToken, err := o.token()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return fmt.Sprintf("%s/login", o.mountPath), map[string]interface{}{
+		"role": o.role,
+		"jwt":  idToken,
+	}, nil
+}
+
+// token returns a fresh ID token, refreshing the previous session if
+// possible and otherwise falling back to re-running the configured flow.
+func (o *OIDCMethod) token() (string, error) {
+	endpoints, err := o.discover()
+	if err != nil {
+		return "", fmt.Errorf("error discovering %s OIDC endpoints: %w", o.provider, err)
+	}
+
+	if o.refreshToken != "" {
+		idToken, err := o.refresh(endpoints)
+		if err == nil {
+			return idToken, nil
+		}
+		o.logger.Warn("oidc: refresh failed, falling back to full flow", "error", err)
+		o.refreshToken = ""
+	}
+
+	switch o.flow {
+	case "device":
+		return o.deviceFlow(endpoints)
+	case "code":
+		return o.codeFlow(endpoints)
+	default:
+		return "", fmt.Errorf("unsupported flow %q", o.flow)
+	}
+}
+
+func (o *OIDCMethod) discover() (*providerEndpoints, error) {
+	resp, err := o.client.Get(strings.TrimSuffix(o.issuerURL, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var e providerEndpoints
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// deviceFlow implements the device authorization grant per RFC 8628:
+// it requests a device/user code pair, prints the verification URL and
+// user code to stderr, and polls the token endpoint until the user
+// approves, the code expires, or an unrecoverable error is returned.
+func (o *OIDCMethod) deviceFlow(e *providerEndpoints) (string, error) {
+	form := url.Values{
+		"client_id": {o.clientID},
+		"scope":     {strings.Join(o.scopes, " ")},
+	}
+	resp, err := o.client.PostForm(e.DeviceAuthorizationEndpoint, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var auth struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&auth); err != nil {
+		return "", err
+	}
+
+	if auth.VerificationURIComplete != "" {
+		fmt.Fprintf(os.Stderr, "Complete authentication at: %s\n", auth.VerificationURIComplete)
+	} else {
+		fmt.Fprintf(os.Stderr, "Go to %s and enter code: %s\n", auth.VerificationURI, auth.UserCode)
+	}
+
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second)
+
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		idToken, refreshToken, err := o.pollToken(e.TokenEndpoint, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {auth.DeviceCode},
+			"client_id":   {o.clientID},
+		})
+		switch {
+		case err == errAuthorizationPending:
+			continue
+		case err == errSlowDown:
+			interval += 5 * time.Second
+			continue
+		case err != nil:
+			return "", err
+		default:
+			o.refreshToken = refreshToken
+			return idToken, nil
+		}
+	}
+
+	return "", fmt.Errorf("device code expired before authorization was completed")
+}
+
+// codeFlow implements the standard authorization-code grant: it prints the
+// provider's authorization URL to stderr and exchanges the code the
+// operator pastes back for a token.
+func (o *OIDCMethod) codeFlow(e *providerEndpoints) (string, error) {
+	authURL := fmt.Sprintf("%s?%s", e.AuthorizationEndpoint, url.Values{
+		"response_type": {"code"},
+		"client_id":     {o.clientID},
+		"scope":         {strings.Join(o.scopes, " ")},
+	}.Encode())
+	fmt.Fprintf(os.Stderr, "Complete authentication at: %s\nEnter the resulting code: ", authURL)
+
+	var code string
+	if _, err := fmt.Scanln(&code); err != nil {
+		return "", fmt.Errorf("error reading authorization code: %w", err)
+	}
+
+	idToken, refreshToken, err := o.pollToken(e.TokenEndpoint, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"client_id":     {o.clientID},
+		"client_secret": {o.clientSecret},
+	})
+	if err != nil {
+		return "", err
+	}
+	o.refreshToken = refreshToken
+	return idToken, nil
+}
+
+func (o *OIDCMethod) refresh(e *providerEndpoints) (string, error) {
+	idToken, refreshToken, err := o.pollToken(e.TokenEndpoint, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {o.refreshToken},
+		"client_id":     {o.clientID},
+		"client_secret": {o.clientSecret},
+	})
+	if err != nil {
+		return "", err
+	}
+	// Not every provider reissues a refresh_token on refresh; keep the
+	// existing one rather than blanking it out when they don't.
+	if refreshToken != "" {
+		o.refreshToken = refreshToken
+	}
+	return idToken, nil
+}
+
+var (
+	errAuthorizationPending = fmt.Errorf("authorization_pending")
+	errSlowDown             = fmt.Errorf("slow_down")
+)
+
+func (o *OIDCMethod) pollToken(endpoint string, form url.Values) (idToken, refreshToken string, err error) {
+	resp, err := o.client.PostForm(endpoint, form)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken      string `json:"id_token"`
+		RefreshToken string `json:"refresh_token"`
+		Error        string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", "", err
+	}
+
+	switch body.Error {
+	case "":
+	case "authorization_pending":
+		return "", "", errAuthorizationPending
+	case "slow_down":
+		return "", "", errSlowDown
+	default:
+		return "", "", fmt.Errorf("token endpoint returned error: %s", body.Error)
+	}
+
+	if body.IDToken == "" {
+		return "", "", fmt.Errorf("token endpoint response did not contain an id_token")
+	}
+
+	return body.IDToken, body.RefreshToken, nil
+}
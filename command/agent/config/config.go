@@ -3,9 +3,15 @@ package config
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/errwrap"
@@ -15,6 +21,7 @@ import (
 
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/token"
 )
 
 // Config is the configuration for the vault server.
@@ -35,18 +42,172 @@ type Method struct {
 }
 
 type Sink struct {
-	Type       string
-	WrapTTLRaw interface{}   `hcl:"wrap_ttl"`
-	WrapTTL    time.Duration `hcl:"-"`
-	DHType     string        `hcl:"dh_type"`
-	DHPath     string        `hcl:"dh_path"`
-	AAD        string        `hcl:"aad"`
-	AADEnvVar  string        `hcl:"aad_env_var"`
-	Config     map[string]interface{}
-}
-
-// LoadConfig loads the configuration at the given path, regardless if
-// its a file or directory.
+	Type                   string
+	WrapTTLRaw             interface{}   `hcl:"wrap_ttl"`
+	WrapTTL                time.Duration `hcl:"-"`
+	DHType                 string        `hcl:"dh_type"`
+	DHPath                 string        `hcl:"dh_path"`
+	AAD                    string        `hcl:"aad"`
+	KDF                    string        `hcl:"kdf"`
+	KeyRotationIntervalRaw interface{}   `hcl:"key_rotation_interval"`
+	KeyRotationInterval    time.Duration `hcl:"-"`
+	DeriveKeyInfo          string        `hcl:"derive_key_info"`
+	Config                 map[string]interface{}
+}
+
+// dhKeyLengths gives the expected public key length, in bytes, of the file
+// at 'dh_path' for each supported 'dh_type'. "x25519+kyber768" is a hybrid
+// classical/post-quantum mode whose public key is the concatenation of an
+// X25519 key (32 bytes) and a Kyber768 encapsulation key (1184 bytes).
+var dhKeyLengths = map[string]int{
+	"curve25519":      32,
+	"x448":            56,
+	"x25519+kyber768": 32 + 1184,
+}
+
+// validateDHKeyFile checks that the public key file at dhPath has the
+// length expected for dhType.
+func validateDHKeyFile(dhType, dhPath string) error {
+	fi, err := os.Stat(dhPath)
+	if err != nil {
+		return errwrap.Wrapf("error stat'ing 'dh_path': {{err}}", err)
+	}
+
+	want := dhKeyLengths[dhType]
+	if int(fi.Size()) != want {
+		return fmt.Errorf("expected a %d-byte public key at 'dh_path' for dh_type %q, got %d bytes", want, dhType, fi.Size())
+	}
+
+	return nil
+}
+
+// TemplateFunc resolves the value portion of a '${scheme:value}'
+// placeholder found in a sink or method 'config' string.
+type TemplateFunc func(value string) (string, error)
+
+// templateFuncs holds the resolvers available to ${scheme:value}
+// placeholders, keyed by scheme. 'env' and 'file' are built in;
+// RegisterTemplateFunc is the extension point for anything else, e.g. an
+// operator adding "aws-ssm" to pull values out of AWS SSM Parameter Store.
+// templateFuncsMu guards it, since RegisterTemplateFunc can race with
+// interpolate running on a concurrently parsed file.
+var (
+	templateFuncsMu sync.RWMutex
+	templateFuncs   = map[string]TemplateFunc{
+		"env":  envTemplateFunc,
+		"file": fileTemplateFunc,
+	}
+)
+
+// RegisterTemplateFunc registers a resolver for '${scheme:value}'
+// placeholders in sink and method 'config' values, in addition to the
+// built-in 'env' and 'file' schemes. Must be called before LoadConfig (or
+// equivalent) so the resolver is available while sinks/methods are parsed.
+func RegisterTemplateFunc(scheme string, fn TemplateFunc) {
+	templateFuncsMu.Lock()
+	defer templateFuncsMu.Unlock()
+	templateFuncs[scheme] = fn
+}
+
+func lookupTemplateFunc(scheme string) (TemplateFunc, bool) {
+	templateFuncsMu.RLock()
+	defer templateFuncsMu.RUnlock()
+	fn, ok := templateFuncs[scheme]
+	return fn, ok
+}
+
+func envTemplateFunc(name string) (string, error) {
+	return os.Getenv(name), nil
+}
+
+func fileTemplateFunc(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+var templateRefPattern = regexp.MustCompile(`\$\{([a-zA-Z0-9_-]+):([^}]*)\}`)
+
+// interpolate resolves every '${scheme:value}' placeholder in s via
+// templateFuncs. A string with no placeholders is returned unchanged.
+func interpolate(s string) (string, error) {
+	var resolveErr error
+
+	result := templateRefPattern.ReplaceAllStringFunc(s, func(match string) string {
+		groups := templateRefPattern.FindStringSubmatch(match)
+		scheme, value := groups[1], groups[2]
+
+		fn, ok := lookupTemplateFunc(scheme)
+		if !ok {
+			resolveErr = fmt.Errorf("no template function registered for scheme %q", scheme)
+			return match
+		}
+
+		resolved, err := fn(value)
+		if err != nil {
+			resolveErr = errwrap.Wrapf(fmt.Sprintf("error resolving %q: {{err}}", match), err)
+			return match
+		}
+		return resolved
+	})
+
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	return result, nil
+}
+
+// interpolateMap walks m, replacing every string value (recursively,
+// through nested maps and slices) with its interpolate result. It is run
+// on a sink/method's decoded 'config' stanza after HCL decode but before
+// validation, so 'url', 'password_file', 'aad', and every other config
+// value support '${env:NAME}' and '${file:/path}' placeholders uniformly.
+func interpolateMap(m map[string]interface{}) error {
+	for k, v := range m {
+		nv, err := interpolateValue(v)
+		if err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("error interpolating %q: {{err}}", k), err)
+		}
+		m[k] = nv
+	}
+	return nil
+}
+
+func interpolateValue(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case string:
+		return interpolate(t)
+	case map[string]interface{}:
+		if err := interpolateMap(t); err != nil {
+			return nil, err
+		}
+		return t, nil
+	case []map[string]interface{}:
+		for _, e := range t {
+			if err := interpolateMap(e); err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	case []interface{}:
+		for i, e := range t {
+			nv, err := interpolateValue(e)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = nv
+		}
+		return t, nil
+	default:
+		return v, nil
+	}
+}
+
+// LoadConfig loads the configuration at the given path. If path is a
+// directory, it loads and merges every *.hcl file inside via
+// LoadConfigDir; otherwise it parses path as a single HCL file.
 func LoadConfig(path string, logger log.Logger) (*Config, error) {
 	fi, err := os.Stat(path)
 	if err != nil {
@@ -54,22 +215,105 @@ func LoadConfig(path string, logger log.Logger) (*Config, error) {
 	}
 
 	if fi.IsDir() {
-		return nil, fmt.Errorf("location is a directory, not a file")
+		return LoadConfigDir(path, logger)
 	}
 
-	// Read the file
 	d, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	// Parse!
-	obj, err := hcl.Parse(string(d))
+	return loadConfigString(path, string(d), false)
+}
+
+// LoadConfigDir loads and merges every *.hcl file in path, in lexical
+// order. Scalar fields are last-write-wins, 'sinks' declared in any file's
+// 'auto_auth' block are concatenated, and a 'method' block defined in more
+// than one file is an error naming both files.
+func LoadConfigDir(path string, logger log.Logger) (*Config, error) {
+	entries, err := ioutil.ReadDir(path)
 	if err != nil {
 		return nil, err
 	}
 
-	// Start building the result
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".hcl") {
+			continue
+		}
+		files = append(files, filepath.Join(path, e.Name()))
+	}
+	sort.Strings(files)
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no *.hcl configuration files found in %q", path)
+	}
+
+	result := &Config{AutoAuth: &AutoAuth{}}
+	var methodFile string
+
+	for _, f := range files {
+		d, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		c, err := loadConfigString(f, string(d), true)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.PidFile != "" {
+			result.PidFile = c.PidFile
+		}
+
+		if c.AutoAuth == nil {
+			continue
+		}
+
+		if c.AutoAuth.Method != nil {
+			if methodFile != "" {
+				return nil, fmt.Errorf("duplicate 'method' block in %q, already defined in %q", f, methodFile)
+			}
+			result.AutoAuth.Method = c.AutoAuth.Method
+			methodFile = f
+		}
+
+		result.AutoAuth.Sinks = append(result.AutoAuth.Sinks, c.AutoAuth.Sinks...)
+	}
+
+	switch {
+	case result.AutoAuth.Method == nil:
+		return nil, fmt.Errorf("no 'method' block found across %q", path)
+	case len(result.AutoAuth.Sinks) == 0:
+		return nil, fmt.Errorf("at least one 'sink' block must be provided across %q", path)
+	}
+
+	return result, nil
+}
+
+// LoadConfigFromReader parses a single HCL config read from r, e.g. to
+// support piping generated configuration on stdin.
+func LoadConfigFromReader(r io.Reader, logger log.Logger) (*Config, error) {
+	d, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadConfigString("<stdin>", string(d), false)
+}
+
+// loadConfigString parses and validates a single HCL document. In lenient
+// mode, used to parse each file of a LoadConfigDir run individually, a
+// document is allowed to omit 'auto_auth' and its 'method'/'sink' blocks
+// entirely so that their presence can be checked once across the merged
+// result instead.
+func loadConfigString(filename, raw string, lenient bool) (*Config, error) {
+	obj, err := hcl.Parse(raw)
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("error parsing %q: {{err}}", filename), err)
+	}
+
 	var result Config
 	if err := hcl.DecodeObject(&result, obj); err != nil {
 		return nil, err
@@ -77,22 +321,29 @@ func LoadConfig(path string, logger log.Logger) (*Config, error) {
 
 	list, ok := obj.Node.(*ast.ObjectList)
 	if !ok {
-		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
+		return nil, fmt.Errorf("error parsing %q: file doesn't contain a root object", filename)
 	}
 
-	if err := parseAutoAuth(&result, list); err != nil {
-		return nil, errwrap.Wrapf("error parsing 'auto_auth': {{err}}", err)
+	if err := parseAutoAuth(&result, list, lenient, filename); err != nil {
+		return nil, err
 	}
 
 	return &result, nil
 }
 
-func parseAutoAuth(result *Config, list *ast.ObjectList) error {
+func parseAutoAuth(result *Config, list *ast.ObjectList, lenient bool, filename string) error {
 	name := "auto_auth"
 
 	autoAuthList := list.Filter(name)
-	if len(autoAuthList.Items) != 1 {
-		return fmt.Errorf("one and only one %q block is required", name)
+	switch {
+	case len(autoAuthList.Items) > 1:
+		return newValidationError(ECodeDuplicateBlock, name, posIn(filename, autoAuthList.Items[1].Pos()),
+			fmt.Errorf("only one %q block is allowed, already defined at %s", name, autoAuthList.Items[0].Pos()))
+	case len(autoAuthList.Items) == 0:
+		if lenient {
+			return nil
+		}
+		return newValidationError(ECodeMissingMethod, name, posIn(filename, token.Pos{}), fmt.Errorf("one and only one %q block is required", name))
 	}
 
 	// Get our item
@@ -107,34 +358,47 @@ func parseAutoAuth(result *Config, list *ast.ObjectList) error {
 
 	subs, ok := item.Val.(*ast.ObjectType)
 	if !ok {
-		return fmt.Errorf("could not parse %q as an object", name)
+		return newValidationError(ECodeInvalidConfig, name, posIn(filename, item.Pos()), fmt.Errorf("could not parse %q as an object", name))
 	}
 	subList := subs.List
 
-	if err := parseMethod(result, subList); err != nil {
-		return errwrap.Wrapf("error parsing 'method': {{err}}", err)
+	var errs *multierror.Error
+
+	methodErr := parseMethod(result, subList, lenient, filename)
+	if methodErr != nil {
+		errs = multierror.Append(errs, methodErr)
 	}
 
-	if err := parseSinks(result, subList); err != nil {
-		return errwrap.Wrapf("error parsing 'sink' stanzas: {{err}}", err)
+	sinksErr := parseSinks(result, subList, lenient, filename)
+	if sinksErr != nil {
+		errs = multierror.Append(errs, sinksErr)
 	}
 
-	switch {
-	case a.Method == nil:
-		return fmt.Errorf("no 'method' block found")
-	case len(a.Sinks) == 0:
-		return fmt.Errorf("at least one 'sink' block must be provided")
+	if !lenient {
+		switch {
+		case methodErr == nil && a.Method == nil:
+			errs = multierror.Append(errs, newValidationError(ECodeMissingMethod, "method", posIn(filename, item.Pos()), errors.New("no 'method' block found")))
+		case sinksErr == nil && len(a.Sinks) == 0:
+			errs = multierror.Append(errs, newValidationError(ECodeMissingSink, "sink", posIn(filename, item.Pos()), errors.New("at least one 'sink' block must be provided")))
+		}
 	}
 
-	return nil
+	return errs.ErrorOrNil()
 }
 
-func parseMethod(result *Config, list *ast.ObjectList) error {
+func parseMethod(result *Config, list *ast.ObjectList, lenient bool, filename string) error {
 	name := "method"
 
 	methodList := list.Filter(name)
-	if len(methodList.Items) != 1 {
-		return fmt.Errorf("one and only one %q block is required", name)
+	switch {
+	case len(methodList.Items) > 1:
+		return newValidationError(ECodeDuplicateBlock, name, posIn(filename, methodList.Items[1].Pos()),
+			fmt.Errorf("only one %q block is allowed, already defined at %s", name, methodList.Items[0].Pos()))
+	case len(methodList.Items) == 0:
+		if lenient {
+			return nil
+		}
+		return newValidationError(ECodeMissingMethod, name, posIn(filename, token.Pos{}), fmt.Errorf("one and only one %q block is required", name))
 	}
 
 	// Get our item
@@ -142,7 +406,15 @@ func parseMethod(result *Config, list *ast.ObjectList) error {
 
 	var m Method
 	if err := hcl.DecodeObject(&m, item.Val); err != nil {
-		return err
+		return newValidationError(ECodeInvalidConfig, name, posIn(filename, item.Pos()), err)
+	}
+
+	if m.Type == "" && len(item.Keys) > 0 {
+		m.Type = strings.ToLower(item.Keys[0].Token.Value().(string))
+	}
+
+	if err := interpolateMap(m.Config); err != nil {
+		return newValidationError(ECodeInterpolation, fmt.Sprintf("method.%s", m.Type), posIn(filename, item.Pos()), err)
 	}
 
 	// Default to Vault's default
@@ -152,96 +424,273 @@ func parseMethod(result *Config, list *ast.ObjectList) error {
 	// Standardize on no trailing slash
 	m.MountPath = strings.TrimSuffix(m.MountPath, "/")
 
+	switch m.Type {
+	case "oidc":
+		if code, err := validateOIDCConfig(m.Config); err != nil {
+			return newValidationError(code, fmt.Sprintf("method.%s", m.Type), posIn(filename, item.Pos()), err)
+		}
+	}
+
 	result.AutoAuth.Method = &m
 	return nil
 }
 
-func parseSinks(result *Config, list *ast.ObjectList) error {
+// validateOIDCConfig checks the 'config' stanza of a 'method "oidc"' block
+// for the keys required to run an OAuth2/OIDC authorization-code or
+// device-code flow: a known 'provider', the client identity, the issuer to
+// discover endpoints from, the Vault JWT auth 'role' to log in as, and a
+// recognized 'flow'. It returns ECodeInvalidConfig for most problems, but
+// the more specific ECodeInvalidFlow when 'flow' itself is the offender, so
+// tooling can distinguish "bad flow value" from the rest of the block.
+func validateOIDCConfig(m map[string]interface{}) (string, error) {
+	for _, key := range []string{"provider", "client_id", "issuer_url", "role"} {
+		raw, ok := m[key]
+		if !ok {
+			return ECodeInvalidConfig, fmt.Errorf("missing %q value", key)
+		}
+		if _, ok := raw.(string); !ok {
+			return ECodeInvalidConfig, fmt.Errorf("cannot convert %q to string", key)
+		}
+	}
+
+	flow := "device"
+	if raw, ok := m["flow"]; ok {
+		var ok bool
+		if flow, ok = raw.(string); !ok {
+			return ECodeInvalidFlow, errors.New("cannot convert 'flow' to string")
+		}
+	}
+	switch flow {
+	case "device", "code":
+	default:
+		return ECodeInvalidFlow, errors.New("invalid value for 'flow', must be 'device' or 'code'")
+	}
+
+	if raw, ok := m["client_secret_file"]; ok {
+		if _, ok := raw.(string); !ok {
+			return ECodeInvalidConfig, errors.New("cannot convert 'client_secret_file' to string")
+		}
+	}
+
+	if raw, ok := m["scopes"]; ok {
+		scopes, ok := raw.([]interface{})
+		if !ok {
+			return ECodeInvalidConfig, errors.New("cannot convert 'scopes' to a list of strings")
+		}
+		for _, s := range scopes {
+			if _, ok := s.(string); !ok {
+				return ECodeInvalidConfig, fmt.Errorf("cannot convert 'scopes' element %v to string", s)
+			}
+		}
+	}
+
+	return "", nil
+}
+
+func parseSinks(result *Config, list *ast.ObjectList, lenient bool, filename string) error {
 	name := "sink"
 
 	sinkList := list.Filter(name)
 	if len(sinkList.Items) < 1 {
-		return fmt.Errorf("at least one %q block is required", name)
+		if lenient {
+			return nil
+		}
+		return newValidationError(ECodeMissingSink, name, posIn(filename, token.Pos{}), fmt.Errorf("at least one %q block is required", name))
 	}
 
 	var ts []*Sink
+	var errs *multierror.Error
 
 	for _, item := range sinkList.Items {
-		if len(item.Keys) == 0 {
-			return fmt.Errorf("token sink type must be specified")
+		s, err := parseSink(item, filename)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
 		}
+		ts = append(ts, s)
+	}
+
+	if err := errs.ErrorOrNil(); err != nil {
+		return err
+	}
+
+	result.AutoAuth.Sinks = ts
+	return nil
+}
+
+// parseSink parses and validates a single 'sink "type" { ... }' block.
+func parseSink(item *ast.ObjectItem, filename string) (*Sink, error) {
+	if len(item.Keys) == 0 {
+		return nil, newValidationError(ECodeMissingField, "sink", posIn(filename, item.Pos()), errors.New("token sink type must be specified"))
+	}
 
-		tsType := strings.ToLower(item.Keys[0].Token.Value().(string))
+	tsType := strings.ToLower(item.Keys[0].Token.Value().(string))
+	field := fmt.Sprintf("sink.%s", tsType)
 
-		var m map[string]interface{}
-		if err := hcl.DecodeObject(&m, item.Val); err != nil {
-			return multierror.Prefix(err, fmt.Sprintf("sink.%s", tsType))
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, item.Val); err != nil {
+		return nil, newValidationError(ECodeInvalidConfig, field, posIn(filename, item.Pos()), err)
+	}
+
+	if err := interpolateMap(m); err != nil {
+		return nil, newValidationError(ECodeInterpolation, field, posIn(filename, item.Pos()), err)
+	}
+
+	var wrapTTL time.Duration
+	if raw, ok := m["wrap_ttl"]; ok {
+		var err error
+		if wrapTTL, err = parseutil.ParseDurationSecond(raw); err != nil {
+			return nil, newValidationError(ECodeInvalidType, field+".wrap_ttl", posIn(filename, item.Pos()), err)
 		}
+	}
 
-		var wrapTTL time.Duration
-		if raw, ok := m["wrap_ttl"]; ok {
-			var err error
-			if wrapTTL, err = parseutil.ParseDurationSecond(raw); err != nil {
-				return multierror.Prefix(err, fmt.Sprintf("sink.%s", tsType))
-			}
+	var dhType string
+	if raw, ok := m["dh_type"]; ok {
+		var ok bool
+		if dhType, ok = raw.(string); !ok {
+			return nil, newValidationError(ECodeInvalidType, field+".dh_type", posIn(filename, item.Pos()), errors.New("cannot convert 'dh_type' to string"))
+		}
+		if _, ok := dhKeyLengths[dhType]; !ok {
+			return nil, newValidationError(ECodeInvalidDHType, field+".dh_type", posIn(filename, item.Pos()), errors.New("invalid value for 'dh_type'"))
 		}
+	}
 
-		var dhType string
-		if raw, ok := m["dh_type"]; ok {
-			var ok bool
-			if dhType, ok = raw.(string); !ok {
-				return multierror.Prefix(errors.New("cannot convert 'dh_type' to string"), fmt.Sprintf("sink.%s", tsType))
-			}
-			switch dhType {
-			case "curve25519":
-			default:
-				return multierror.Prefix(errors.New("invalid value for 'dh_type'"), fmt.Sprintf("sink.%s", tsType))
-			}
+	var dhPath string
+	if raw, ok := m["dh_path"]; ok {
+		var ok bool
+		if dhPath, ok = raw.(string); !ok {
+			return nil, newValidationError(ECodeInvalidType, field+".dh_path", posIn(filename, item.Pos()), errors.New("cannot convert 'dh_path' to string"))
 		}
+	}
 
-		var dhPath string
-		if raw, ok := m["dh_path"]; ok {
-			var ok bool
-			if dhPath, ok = raw.(string); !ok {
-				return multierror.Prefix(errors.New("cannot convert 'dh_path' to string"), fmt.Sprintf("sink.%s", tsType))
-			}
+	var kdf string
+	if raw, ok := m["kdf"]; ok {
+		var ok bool
+		if kdf, ok = raw.(string); !ok {
+			return nil, newValidationError(ECodeInvalidType, field+".kdf", posIn(filename, item.Pos()), errors.New("cannot convert 'kdf' to string"))
+		}
+		switch kdf {
+		case "hkdf-sha256", "hkdf-sha512":
+		default:
+			return nil, newValidationError(ECodeInvalidKDF, field+".kdf", posIn(filename, item.Pos()), errors.New("invalid value for 'kdf'"))
 		}
+	} else {
+		kdf = "hkdf-sha256"
+	}
 
-		var aad string
-		if raw, ok := m["aad"]; ok {
-			var ok bool
-			if aad, ok = raw.(string); !ok {
-				return multierror.Prefix(errors.New("cannot convert 'aad' to string"), fmt.Sprintf("sink.%s", tsType))
-			}
-		} else if raw, ok := m["aad_env_var"]; ok {
-			var ok bool
-			var envvar string
-			if envvar, ok = raw.(string); !ok {
-				return multierror.Prefix(errors.New("cannot convert 'aad_env_var' to string"), fmt.Sprintf("sink.%s", tsType))
-			}
-			aad = os.Getenv(envvar)
+	var keyRotationInterval time.Duration
+	if raw, ok := m["key_rotation_interval"]; ok {
+		var err error
+		if keyRotationInterval, err = parseutil.ParseDurationSecond(raw); err != nil {
+			return nil, newValidationError(ECodeInvalidType, field+".key_rotation_interval", posIn(filename, item.Pos()), err)
+		}
+		if dhType == "" {
+			return nil, newValidationError(ECodeInvalidConfig, field+".key_rotation_interval", posIn(filename, item.Pos()), errors.New("'key_rotation_interval' requires 'dh_type' to be set"))
 		}
+	}
 
-		switch {
-		case dhPath == "" && dhType == "":
-			if aad != "" {
-				return multierror.Prefix(errors.New("specifying AAD data without 'dh_type' does not make sense"), fmt.Sprintf("sink.%s", tsType))
-			}
-		case dhPath != "" && dhType != "":
-		default:
-			return multierror.Prefix(errors.New("'dh_type' and 'dh_path' must be specified together"), fmt.Sprintf("sink.%s", tsType))
+	var deriveKeyInfo string
+	if raw, ok := m["derive_key_info"]; ok {
+		var ok bool
+		if deriveKeyInfo, ok = raw.(string); !ok {
+			return nil, newValidationError(ECodeInvalidType, field+".derive_key_info", posIn(filename, item.Pos()), errors.New("cannot convert 'derive_key_info' to string"))
 		}
+	}
 
-		ts = append(ts, &Sink{
-			Type:    tsType,
-			WrapTTL: wrapTTL,
-			DHType:  dhType,
-			DHPath:  dhPath,
-			AAD:     aad,
-			Config:  m,
-		})
+	var aad string
+	if raw, ok := m["aad"]; ok {
+		var ok bool
+		if aad, ok = raw.(string); !ok {
+			return nil, newValidationError(ECodeInvalidType, field+".aad", posIn(filename, item.Pos()), errors.New("cannot convert 'aad' to string"))
+		}
+	}
+
+	if _, ok := m["aad_env_var"]; ok {
+		return nil, newValidationError(ECodeInvalidConfig, field+".aad_env_var", posIn(filename, item.Pos()),
+			errors.New("'aad_env_var' was removed, set 'aad' to '${env:NAME}' instead"))
+	}
+
+	switch {
+	case dhPath == "" && dhType == "":
+		if aad != "" {
+			return nil, newValidationError(ECodeInvalidConfig, field+".aad", posIn(filename, item.Pos()), errors.New("specifying AAD data without 'dh_type' does not make sense"))
+		}
+	case dhPath != "" && dhType != "":
+		if err := validateDHKeyFile(dhType, dhPath); err != nil {
+			return nil, newValidationError(ECodeInvalidConfig, field+".dh_path", posIn(filename, item.Pos()), err)
+		}
+	default:
+		return nil, newValidationError(ECodeInvalidConfig, field, posIn(filename, item.Pos()), errors.New("'dh_type' and 'dh_path' must be specified together"))
+	}
+
+	switch tsType {
+	case "webdav":
+		if err := validateWebDAVConfig(m); err != nil {
+			return nil, newValidationError(ECodeInvalidConfig, field, posIn(filename, item.Pos()), err)
+		}
+	}
+
+	return &Sink{
+		Type:                tsType,
+		WrapTTL:             wrapTTL,
+		DHType:              dhType,
+		DHPath:              dhPath,
+		AAD:                 aad,
+		KDF:                 kdf,
+		KeyRotationInterval: keyRotationInterval,
+		DeriveKeyInfo:       deriveKeyInfo,
+		Config:              m,
+	}, nil
+}
+
+// validateWebDAVConfig checks the 'config' stanza of a 'sink "webdav"' block
+// for the keys required by the webdav sink: a reachable 'url', a 'path' to
+// write the token to, and mutually exclusive ways of supplying the
+// basic-auth password.
+func validateWebDAVConfig(m map[string]interface{}) error {
+	rawURL, ok := m["url"]
+	if !ok {
+		return errors.New("missing 'url' value")
+	}
+	urlStr, ok := rawURL.(string)
+	if !ok {
+		return errors.New("cannot convert 'url' to string")
+	}
+	parsed, err := url.Parse(urlStr)
+	if err != nil {
+		return errwrap.Wrapf("error parsing 'url': {{err}}", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return errors.New("'url' scheme must be http or https")
+	}
+
+	if raw, ok := m["path"]; !ok {
+		return errors.New("missing 'path' value")
+	} else if _, ok := raw.(string); !ok {
+		return errors.New("cannot convert 'path' to string")
+	}
+
+	rawPasswordFile, hasPasswordFile := m["password_file"]
+	rawPasswordEnvVar, hasPasswordEnvVar := m["password_env_var"]
+	if hasPasswordFile && hasPasswordEnvVar {
+		return errors.New("'password_file' and 'password_env_var' are mutually exclusive")
+	}
+	if hasPasswordFile {
+		if _, ok := rawPasswordFile.(string); !ok {
+			return errors.New("cannot convert 'password_file' to string")
+		}
+	}
+	if hasPasswordEnvVar {
+		if _, ok := rawPasswordEnvVar.(string); !ok {
+			return errors.New("cannot convert 'password_env_var' to string")
+		}
+	}
+
+	if raw, ok := m["tls_skip_verify"]; ok {
+		if _, ok := raw.(bool); !ok {
+			return errors.New("cannot convert 'tls_skip_verify' to bool")
+		}
 	}
 
-	result.AutoAuth.Sinks = ts
 	return nil
 }
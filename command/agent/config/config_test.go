@@ -0,0 +1,33 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestValidateWebDAVConfig_PasswordTypes(t *testing.T) {
+	base := map[string]interface{}{
+		"url":  "https://example.com",
+		"path": "/token",
+	}
+
+	cases := []struct {
+		name string
+		key  string
+		val  interface{}
+	}{
+		{"password_file", "password_file", 123},
+		{"password_env_var", "password_env_var", true},
+	}
+
+	for _, c := range cases {
+		m := map[string]interface{}{}
+		for k, v := range base {
+			m[k] = v
+		}
+		m[c.key] = c.val
+
+		if err := validateWebDAVConfig(m); err == nil {
+			t.Errorf("%s: expected an error for non-string %q, got nil", c.name, c.key)
+		}
+	}
+}
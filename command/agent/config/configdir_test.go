@@ -0,0 +1,54 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+)
+
+func TestLoadConfigDir_DuplicateMethod(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agent-config-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const methodBlock = `
+auto_auth {
+	method "aws" {
+		mount_path = "auth/aws"
+	}
+}
+`
+	const sinkBlock = `
+auto_auth {
+	sink "file" {
+		config = {
+			path = "/tmp/token"
+		}
+	}
+}
+`
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "01-method.hcl"), []byte(methodBlock), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "02-method.hcl"), []byte(methodBlock), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "03-sink.hcl"), []byte(sinkBlock), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LoadConfigDir(dir, log.NewNullLogger())
+	if err == nil {
+		t.Fatal("expected an error for duplicate 'method' blocks across files, got nil")
+	}
+	if !strings.Contains(err.Error(), "duplicate 'method' block") {
+		t.Errorf("expected a duplicate 'method' block error, got: %v", err)
+	}
+}
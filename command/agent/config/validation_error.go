@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl/hcl/token"
+)
+
+// Machine-readable codes attached to ValidationError, so tooling (an LSP,
+// `vault agent validate`) can key off the failure instead of parsing
+// Error() text.
+const (
+	ECodeDuplicateBlock = "E_DUPLICATE_BLOCK"
+	ECodeMissingMethod  = "E_MISSING_METHOD"
+	ECodeMissingSink    = "E_MISSING_SINK"
+	ECodeMissingField   = "E_MISSING_FIELD"
+	ECodeInvalidType    = "E_INVALID_TYPE"
+	ECodeInvalidDHType  = "E_INVALID_DH_TYPE"
+	ECodeInvalidKDF     = "E_INVALID_KDF"
+	ECodeInvalidFlow    = "E_INVALID_FLOW"
+	ECodeInvalidConfig  = "E_INVALID_CONFIG"
+	ECodeInterpolation  = "E_INTERPOLATION"
+)
+
+// ValidationError is returned by LoadConfig and its helpers for problems
+// found in the HCL source, carrying enough detail for a caller to render a
+// compiler-style diagnostic instead of a flat error string.
+type ValidationError struct {
+	// Code is a machine-readable identifier for the kind of problem, e.g.
+	// ECodeInvalidDHType.
+	Code string
+	// Field is the dotted path of the offending value, e.g. "sink.webdav.url".
+	Field string
+	// Pos is the position of the offending block in the source file.
+	Pos token.Pos
+	// Err is the underlying problem.
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Pos.Filename != "" {
+		return fmt.Sprintf("%s:%d:%d: %s: %s (%s)", e.Pos.Filename, e.Pos.Line, e.Pos.Column, e.Field, e.Err, e.Code)
+	}
+	return fmt.Sprintf("%s: %s (%s)", e.Field, e.Err, e.Code)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Format writes a compiler-style caret diagnostic for e to w: the message,
+// followed by the offending source line (when Pos names a readable file)
+// with a caret under the offending column.
+func (e *ValidationError) Format(w io.Writer) {
+	fmt.Fprintf(w, "%s: %s [%s]\n", e.Field, e.Err, e.Code)
+
+	if e.Pos.Filename == "" {
+		return
+	}
+
+	line, err := sourceLine(e.Pos.Filename, e.Pos.Line)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(w, "  --> %s:%d:%d\n", e.Pos.Filename, e.Pos.Line, e.Pos.Column)
+	fmt.Fprintf(w, "   | %s\n", line)
+	fmt.Fprintf(w, "   | %s^\n", strings.Repeat(" ", e.Pos.Column-1))
+}
+
+func sourceLine(filename string, lineNo int) (string, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(string(b), "\n")
+	if lineNo < 1 || lineNo > len(lines) {
+		return "", fmt.Errorf("line %d out of range for %q", lineNo, filename)
+	}
+	return lines[lineNo-1], nil
+}
+
+// FormatErrors writes a caret diagnostic (via ValidationError.Format) for
+// every *ValidationError in err to w, in order. err may be a single
+// *ValidationError, a *multierror.Error aggregating several, or any other
+// error, whose Error() text is written as a plain line.
+func FormatErrors(w io.Writer, err error) {
+	if err == nil {
+		return
+	}
+
+	if merr, ok := err.(*multierror.Error); ok {
+		for _, e := range merr.Errors {
+			FormatErrors(w, e)
+		}
+		return
+	}
+
+	if verr, ok := err.(*ValidationError); ok {
+		verr.Format(w)
+		return
+	}
+
+	fmt.Fprintln(w, err.Error())
+}
+
+func newValidationError(code, field string, pos token.Pos, err error) *ValidationError {
+	return &ValidationError{Code: code, Field: field, Pos: pos, Err: err}
+}
+
+// posIn returns p with its Filename set to filename. hcl.Parse/DecodeObject
+// never stamp a token.Pos's Filename themselves, so every ValidationError
+// constructed while parsing a given file must run its Pos through this
+// before use, or Error()/Format() silently have no file:line:column to show.
+func posIn(filename string, p token.Pos) token.Pos {
+	p.Filename = filename
+	return p
+}
@@ -0,0 +1,94 @@
+package sink
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/cloudflare/circl/dh/x448"
+	"github.com/cloudflare/circl/kem/kyber/kyber768"
+	"golang.org/x/crypto/curve25519"
+)
+
+// Encapsulate runs one side of a key-agreement for dhType against remotePub,
+// the operator's long-term public key read from 'dh_path' (its expected
+// length per dhType is enforced by config.validateDHKeyFile before this is
+// ever called). It generates a fresh ephemeral key pair, computes the
+// shared secret from the ephemeral private component and remotePub, and
+// returns that secret alongside the ephemeral public value.
+//
+// Only ephemeralPub is ever meant to leave the agent: it's what gets sent
+// to the sink's destination so the holder of the private key matching
+// remotePub can recompute the same sharedSecret on their end. sharedSecret
+// (and the symmetric key DeriveKey produces from it) must never be
+// transmitted or persisted anywhere the sink's destination can read.
+func Encapsulate(dhType string, remotePub []byte) (sharedSecret, ephemeralPub []byte, err error) {
+	switch dhType {
+	case "curve25519":
+		if len(remotePub) != 32 {
+			return nil, nil, fmt.Errorf("curve25519: expected a 32-byte public key, got %d bytes", len(remotePub))
+		}
+		var priv [32]byte
+		if _, err := rand.Read(priv[:]); err != nil {
+			return nil, nil, err
+		}
+		var pub [32]byte
+		curve25519.ScalarBaseMult(&pub, &priv)
+
+		var remote [32]byte
+		copy(remote[:], remotePub)
+		var shared [32]byte
+		curve25519.ScalarMult(&shared, &priv, &remote)
+
+		return shared[:], pub[:], nil
+
+	case "x448":
+		if len(remotePub) != x448.Size {
+			return nil, nil, fmt.Errorf("x448: expected a %d-byte public key, got %d bytes", x448.Size, len(remotePub))
+		}
+		var priv, pub x448.Key
+		if _, err := rand.Read(priv[:]); err != nil {
+			return nil, nil, err
+		}
+		x448.KeyGen(&pub, &priv)
+
+		var remote, shared x448.Key
+		copy(remote[:], remotePub)
+		if !x448.Shared(&shared, &priv, &remote) {
+			return nil, nil, fmt.Errorf("x448: remote public key produced a low-order shared secret")
+		}
+
+		return shared[:], pub[:], nil
+
+	case "x25519+kyber768":
+		wantLen := 32 + kyber768.PublicKeySize
+		if len(remotePub) != wantLen {
+			return nil, nil, fmt.Errorf("x25519+kyber768: expected a %d-byte public key, got %d bytes", wantLen, len(remotePub))
+		}
+
+		var priv [32]byte
+		if _, err := rand.Read(priv[:]); err != nil {
+			return nil, nil, err
+		}
+		var pub [32]byte
+		curve25519.ScalarBaseMult(&pub, &priv)
+
+		var remoteClassical [32]byte
+		copy(remoteClassical[:], remotePub[:32])
+		var classicalShared [32]byte
+		curve25519.ScalarMult(&classicalShared, &priv, &remoteClassical)
+
+		var kyberPub kyber768.PublicKey
+		kyberPub.Unpack(remotePub[32:])
+
+		ct := make([]byte, kyber768.CiphertextSize)
+		pqShared := make([]byte, kyber768.SharedKeySize)
+		kyberPub.EncapsulateTo(ct, pqShared, nil)
+
+		shared := append(append([]byte{}, classicalShared[:]...), pqShared...)
+		ephemeral := append(append([]byte{}, pub[:]...), ct...)
+		return shared, ephemeral, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported dh_type %q", dhType)
+	}
+}
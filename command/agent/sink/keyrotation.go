@@ -0,0 +1,60 @@
+package sink
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"golang.org/x/crypto/hkdf"
+)
+
+// DeriveKey derives a keyLen-byte symmetric key from secret using the named
+// kdf ("hkdf-sha256" or "hkdf-sha512"). info is mixed in as the HKDF info
+// parameter for domain separation and should come from the sink's
+// 'derive_key_info' config value.
+func DeriveKey(kdf string, secret, salt []byte, info string, keyLen int) ([]byte, error) {
+	var newHash func() hash.Hash
+	switch kdf {
+	case "", "hkdf-sha256":
+		newHash = sha256.New
+	case "hkdf-sha512":
+		newHash = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported kdf %q", kdf)
+	}
+
+	key := make([]byte, keyLen)
+	if _, err := io.ReadFull(hkdf.New(newHash, secret, salt, []byte(info)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// StartKeyRotation runs rotate on every tick of interval until stop is
+// closed, logging (rather than returning) any error rotate produces since
+// it runs on its own goroutine. It is used by sinks configured with
+// 'key_rotation_interval' to periodically derive a fresh symmetric key and
+// rewrite the sink file with it.
+func StartKeyRotation(logger log.Logger, interval time.Duration, stop <-chan struct{}, rotate func() error) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := rotate(); err != nil {
+				logger.Error("error rotating sink key", "error", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,36 @@
+// Package sink defines the interface implemented by vault-agent token
+// sinks and the shared config type used to construct them.
+package sink
+
+import (
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+)
+
+// Sink is a place that auto-auth tokens can be delivered to.
+type Sink interface {
+	// WriteToken writes the given (possibly wrapped/encrypted) token to the
+	// sink's destination.
+	WriteToken(token string) error
+}
+
+// SinkConfig is the configuration passed into a sink's constructor,
+// containing the common bits all sinks need plus the type-specific values
+// pulled from the HCL 'config' stanza.
+type SinkConfig struct {
+	Logger  log.Logger
+	Config  map[string]interface{}
+	WrapTTL time.Duration
+	DHType  string
+	DHPath  string
+	AAD     string
+
+	// KDF, KeyRotationInterval, and DeriveKeyInfo come from the sink's
+	// 'kdf', 'key_rotation_interval', and 'derive_key_info' config values;
+	// a sink that wants to periodically rewrite a derived symmetric key
+	// (see DeriveKey and StartKeyRotation) uses these to do so.
+	KDF                 string
+	KeyRotationInterval time.Duration
+	DeriveKeyInfo       string
+}
@@ -0,0 +1,194 @@
+// Package webdav implements a vault-agent token sink that delivers the
+// wrapped/encrypted token to a remote WebDAV endpoint (Nextcloud, ownCloud,
+// or any generic WebDAV server), so that fleets can distribute tokens via
+// existing shared storage instead of local disk.
+package webdav
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+
+	"github.com/hashicorp/vault/command/agent/sink"
+)
+
+// SinkWebDAV writes auto-auth tokens to a remote WebDAV endpoint.
+type SinkWebDAV struct {
+	logger log.Logger
+	client *http.Client
+	base   string
+	user   string
+	pass   string
+	path   string
+
+	// dhType, dhRemotePubPath, kdf, and deriveKeyInfo drive rotateKey, run
+	// on keyRotationInterval by StartKeyRotation when keyRotationInterval
+	// is set. dhRemotePubPath names the operator's long-term DH/KEM public
+	// key file (config's 'dh_path'); it is never secret.
+	dhType              string
+	dhRemotePubPath     string
+	kdf                 string
+	keyRotationInterval time.Duration
+	deriveKeyInfo       string
+	stopKeyRotation     chan struct{}
+
+	keyMu      sync.RWMutex
+	currentKey []byte
+}
+
+// New creates a new webdav sink from the given sink config. It expects
+// 'url', 'path', and optionally 'username' plus 'password_file' (or
+// 'password_env_var') in conf.Config, as validated by config.parseSinks.
+func New(conf *sink.SinkConfig) (*SinkWebDAV, error) {
+	s := &SinkWebDAV{
+		logger: conf.Logger,
+		base:   strings.TrimSuffix(conf.Config["url"].(string), "/"),
+		path:   conf.Config["path"].(string),
+
+		dhType:              conf.DHType,
+		dhRemotePubPath:     conf.DHPath,
+		kdf:                 conf.KDF,
+		keyRotationInterval: conf.KeyRotationInterval,
+		deriveKeyInfo:       conf.DeriveKeyInfo,
+	}
+
+	if raw, ok := conf.Config["username"]; ok {
+		s.user, _ = raw.(string)
+	}
+
+	if raw, ok := conf.Config["password_file"]; ok {
+		b, err := ioutil.ReadFile(raw.(string))
+		if err != nil {
+			return nil, fmt.Errorf("error reading password_file: %w", err)
+		}
+		s.pass = strings.TrimSpace(string(b))
+	} else if raw, ok := conf.Config["password_env_var"]; ok {
+		s.pass = os.Getenv(raw.(string))
+	}
+
+	transport := &http.Transport{}
+	if raw, ok := conf.Config["tls_skip_verify"]; ok && raw.(bool) {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	s.client = &http.Client{Transport: transport}
+
+	if s.keyRotationInterval > 0 {
+		s.stopKeyRotation = make(chan struct{})
+		go sink.StartKeyRotation(s.logger, s.keyRotationInterval, s.stopKeyRotation, s.rotateKey)
+	}
+
+	return s, nil
+}
+
+// rotateKey runs one side of a fresh Diffie-Hellman/KEM key agreement
+// against the operator's long-term public key at dhRemotePubPath, derives a
+// symmetric key from the resulting shared secret, and keeps it in memory
+// for future use. It uploads only the ephemeral public value produced by
+// the agreement to the sink's '.key' path — never the shared secret or the
+// derived key itself — so that only the holder of the private key matching
+// dhRemotePubPath can recompute the same key.
+func (s *SinkWebDAV) rotateKey() error {
+	remotePub, err := ioutil.ReadFile(s.dhRemotePubPath)
+	if err != nil {
+		return fmt.Errorf("error reading dh_path: %w", err)
+	}
+
+	sharedSecret, ephemeralPub, err := sink.Encapsulate(s.dhType, remotePub)
+	if err != nil {
+		return fmt.Errorf("error performing key agreement: %w", err)
+	}
+
+	key, err := sink.DeriveKey(s.kdf, sharedSecret, nil, s.deriveKeyInfo, 32)
+	if err != nil {
+		return fmt.Errorf("error deriving key: %w", err)
+	}
+
+	s.keyMu.Lock()
+	s.currentKey = key
+	s.keyMu.Unlock()
+
+	keyPath := s.path + ".key"
+	tmpPath := keyPath + ".tmp"
+
+	if err := s.put(tmpPath, hex.EncodeToString(ephemeralPub)); err != nil {
+		return fmt.Errorf("error uploading to %q: %w", tmpPath, err)
+	}
+
+	if err := s.move(tmpPath, keyPath); err != nil {
+		return fmt.Errorf("error moving %q to %q: %w", tmpPath, keyPath, err)
+	}
+
+	return nil
+}
+
+// WriteToken uploads token to a temporary path and then issues a WebDAV
+// MOVE into the final path, so that consumers polling the sink file never
+// observe a partial write.
+func (s *SinkWebDAV) WriteToken(token string) error {
+	tmpPath := s.path + ".tmp"
+
+	if err := s.put(tmpPath, token); err != nil {
+		return fmt.Errorf("error uploading to %q: %w", tmpPath, err)
+	}
+
+	if err := s.move(tmpPath, s.path); err != nil {
+		return fmt.Errorf("error moving %q to %q: %w", tmpPath, s.path, err)
+	}
+
+	return nil
+}
+
+func (s *SinkWebDAV) put(path, body string) error {
+	req, err := http.NewRequest(http.MethodPut, s.base+path, bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	s.authenticate(req)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *SinkWebDAV) move(src, dst string) error {
+	req, err := http.NewRequest("MOVE", s.base+src, nil)
+	if err != nil {
+		return err
+	}
+	s.authenticate(req)
+	req.Header.Set("Destination", s.base+dst)
+	req.Header.Set("Overwrite", "T")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *SinkWebDAV) authenticate(req *http.Request) {
+	if s.user != "" {
+		req.SetBasicAuth(s.user, s.pass)
+	}
+}